@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	aw "github.com/deanishe/awgo"
+	"github.com/deanishe/awgo/util"
+
+	"github.com/deanishe/alfred-firefox/archiver"
+)
+
+// title and archiveID are set by the corresponding -title and -archive
+// flags registered alongside the other flags in main.go.
+var (
+	title     string
+	archiveID string
+)
+
+// includeWARC is set by the -archive-warc flag registered alongside the
+// other flags in main.go. It enables recording a full WARC snapshot
+// alongside the readable HTML copy, off by default since most pages only
+// need the extracted article.
+var includeWARC bool
+
+// archiveJobEnv marks that this invocation of the binary is the
+// background job runArchive spawns for the actual fetch, rather than the
+// initial "archive" command Alfred calls directly.
+const archiveJobEnv = "ALFRED_FIREFOX_ARCHIVE_JOB"
+
+// archiveDir is where offline snapshots and the archive index are stored.
+func archiveDir() string {
+	return filepath.Join(wf.DataDir(), "archives")
+}
+
+func archiveIndex() (*archiver.Index, error) {
+	return archiver.OpenIndex(filepath.Join(archiveDir(), "index.json"))
+}
+
+// runArchive downloads URL, extracts a readable copy and records it in the
+// archive index. It's invoked directly (via the "archive" CMD) and also
+// backs the archiveAction registered in urlActions.
+//
+// The fetch can take a while, so the initial invocation just starts a
+// background job (mirroring checkFavicons/checkHistoryIndex) and returns
+// immediately, rather than blocking Alfred on the request; the job
+// re-invokes this same command with archiveJobEnv set, at which point
+// doArchive does the actual work.
+func runArchive(_ []string) error {
+	wf.Configure(aw.TextErrors(true))
+
+	if os.Getenv(archiveJobEnv) == "" {
+		if wf.IsRunning("archive") {
+			return nil
+		}
+		cmd := exec.Command(os.Args[0],
+			"-url", URL,
+			"-title", title,
+			"-bookmark", bookmarkID,
+			"-tab", strconv.Itoa(tabID),
+			"-profile", profileName,
+			"-archive-warc="+strconv.FormatBool(includeWARC),
+			"archive")
+		cmd.Env = append(os.Environ(), archiveJobEnv+"=1")
+		return wf.RunInBackground("archive", cmd)
+	}
+
+	return doArchive(URL, title, bookmarkID, tabID)
+}
+
+// doArchive fetches rawURL, extracts a readable copy and records it in the
+// archive index. It's the shared implementation behind both runArchive's
+// background job and archiveAction.Run.
+func doArchive(rawURL, pageTitle, bmID string, tID int) error {
+	getLogger().Debug("archiving page", "url", rawURL, "bookmark_id", bmID, "tab_id", tID)
+
+	idx, err := archiveIndex()
+	if err != nil {
+		return fmt.Errorf("open archive index: %w", err)
+	}
+
+	a := archiver.New(archiveDir())
+	a.IncludeWARC = includeWARC
+	snap, err := a.Archive(rawURL, pageTitle, bmID, tID)
+	if err != nil {
+		return err
+	}
+
+	return idx.Put(snap)
+}
+
+// runArchives lists locally saved offline snapshots as Alfred items.
+func runArchives(_ []string) error {
+	idx, err := archiveIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range idx.All() {
+		it := wf.NewItem(snap.Title).
+			Subtitle(snap.URL).
+			Arg("file://"+snap.HTMLPath).
+			UID(snap.ID).
+			Valid(true).
+			Icon(iconArchive).
+			Var("CMD", "url").
+			Var("ACTION", urlDefault).
+			Var("URL", "file://"+snap.HTMLPath)
+
+		it.NewModifier(aw.ModCmd).
+			Subtitle("Re-archive").
+			Arg(snap.URL).
+			Icon(iconArchive).
+			Var("CMD", "archive").
+			Var("URL", snap.URL).
+			Var("TITLE", snap.Title)
+
+		it.NewModifier(aw.ModShift).
+			Subtitle("Delete Offline Copy").
+			Arg(snap.ID).
+			Icon(iconDelete).
+			Var("CMD", "delete-archive").
+			Var("ARCHIVE", snap.ID)
+	}
+
+	if query != "" {
+		_ = wf.Filter(query)
+	}
+
+	wf.WarnEmpty("No Archives", "Save a page first with ⌥↩ on a history, bookmark or tab item")
+	wf.SendFeedback()
+	return nil
+}
+
+// runDeleteArchive removes a snapshot from disk and the archive index.
+func runDeleteArchive(_ []string) error {
+	wf.Configure(aw.TextErrors(true))
+	getLogger().Debug("deleting archive", "archive_id", archiveID)
+
+	idx, err := archiveIndex()
+	if err != nil {
+		return err
+	}
+	snap, ok := idx.Get(archiveID)
+	if !ok {
+		return fmt.Errorf("no such archive %q", archiveID)
+	}
+
+	if _, err := util.RunCmd(exec.Command("rm", "-rf", filepath.Dir(snap.HTMLPath))); err != nil {
+		return err
+	}
+	return idx.Delete(archiveID)
+}
+
+// archiveAction records an offline copy of a URL. It's registered in
+// urlActions under its Name(), the value runActions passes as the action,
+// so it shows up alongside the other URL actions in runActions and
+// runURLAction can dispatch to it.
+type archiveAction struct{}
+
+func (archiveAction) Name() string   { return "Save Offline Copy" }
+func (archiveAction) Icon() *aw.Icon { return iconArchive }
+
+// Run saves an offline copy of rawURL. runURLAction calls it with the same
+// URL already held in the URL package var, so it delegates to runArchive
+// rather than re-fetching and re-indexing the page itself - that keeps
+// this action's bookmark/tab bookkeeping and background-job behaviour in
+// sync with the "archive" CMD instead of drifting out of step with it.
+func (archiveAction) Run(rawURL string) error {
+	if _, err := url.Parse(rawURL); err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	return runArchive(nil)
+}
+
+func init() {
+	urlActions[archiveAction{}.Name()] = archiveAction{}
+}
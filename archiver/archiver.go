@@ -0,0 +1,136 @@
+// Package archiver fetches web pages and stores readable, offline copies
+// of them on disk, similar to shiori's archiver.
+package archiver
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-shiori/go-readability"
+)
+
+// Snapshot is a single archived copy of a page.
+type Snapshot struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	BookmarkID string    `json:"bookmark_id,omitempty"`
+	TabID      int       `json:"tab_id,omitempty"`
+	HTMLPath   string    `json:"html_path"`
+	WARCPath   string    `json:"warc_path,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Archiver downloads pages and writes readable HTML (and, optionally, a
+// full WARC snapshot) to dir.
+type Archiver struct {
+	Dir         string // data directory snapshots are written under
+	IncludeWARC bool   // also record the full page as a WARC file
+
+	client *http.Client
+}
+
+// New returns an Archiver that stores snapshots under dir.
+func New(dir string) *Archiver {
+	return &Archiver{
+		Dir:    dir,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Archive fetches url, extracts a readable article and writes it (plus an
+// optional WARC record) to a.Dir. The returned Snapshot's ID is derived
+// from url and is stable across re-archives.
+func (a *Archiver) Archive(rawURL, title, bookmarkID string, tabID int) (*Snapshot, error) {
+	id := pageID(rawURL)
+	snapDir := filepath.Join(a.Dir, id)
+	if err := os.MkdirAll(snapDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot dir: %w", err)
+	}
+
+	resp, err := a.client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %q: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Buffer the body so it can be fed to both readability and writeWARC -
+	// resp.Body can only be read once, and readability.FromReader would
+	// otherwise drain it before writeWARC got a chance to record it.
+	body, err := readAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", rawURL, err)
+	}
+
+	article, err := readability.FromReader(bytes.NewReader(body), resp.Request.URL)
+	if err != nil {
+		return nil, fmt.Errorf("extract content from %q: %w", rawURL, err)
+	}
+
+	if title == "" {
+		title = article.Title
+	}
+
+	htmlPath := filepath.Join(snapDir, "index.html")
+	if err := os.WriteFile(htmlPath, []byte(article.Content), 0o644); err != nil {
+		return nil, fmt.Errorf("write article HTML: %w", err)
+	}
+
+	snap := &Snapshot{
+		ID:         id,
+		URL:        rawURL,
+		Title:      title,
+		BookmarkID: bookmarkID,
+		TabID:      tabID,
+		HTMLPath:   htmlPath,
+		CreatedAt:  time.Now(),
+	}
+
+	if a.IncludeWARC {
+		warcPath := filepath.Join(snapDir, "page.warc")
+		if err := a.writeWARC(warcPath, rawURL, body); err != nil {
+			return nil, fmt.Errorf("write WARC: %w", err)
+		}
+		snap.WARCPath = warcPath
+	}
+
+	return snap, nil
+}
+
+// writeWARC records body, the raw response for rawURL, as a minimal
+// single-record WARC file. Asset inlining (images, CSS) is left to a
+// follow-up pass; for now the response body is recorded as-is.
+func (a *Archiver) writeWARC(path, rawURL string, body []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := fmt.Sprintf(
+		"WARC/1.0\r\nWARC-Type: response\r\nWARC-Target-URI: %s\r\nWARC-Date: %s\r\nContent-Length: %d\r\n\r\n",
+		rawURL, time.Now().UTC().Format(time.RFC3339), len(body))
+
+	if _, err := io.WriteString(f, header); err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	return err
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	return io.ReadAll(r)
+}
+
+// pageID returns a stable, filesystem-safe identifier for a URL.
+func pageID(rawURL string) string {
+	sum := sha1.Sum([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
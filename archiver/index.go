@@ -0,0 +1,76 @@
+package archiver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Index is the on-disk record of every Snapshot the workflow has taken,
+// keyed by Snapshot.ID. It is persisted as a single JSON file rather than
+// a database, matching the size and lifetime of the rest of the workflow's
+// cached data.
+type Index struct {
+	path  string
+	items map[string]*Snapshot
+}
+
+// OpenIndex loads the index from path, creating an empty one if the file
+// doesn't exist yet.
+func OpenIndex(path string) (*Index, error) {
+	idx := &Index{path: path, items: map[string]*Snapshot{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &idx.items); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Put adds or replaces a snapshot and persists the index.
+func (idx *Index) Put(snap *Snapshot) error {
+	idx.items[snap.ID] = snap
+	return idx.save()
+}
+
+// Delete removes a snapshot by ID and persists the index.
+func (idx *Index) Delete(id string) error {
+	delete(idx.items, id)
+	return idx.save()
+}
+
+// Get returns the snapshot for id, if any.
+func (idx *Index) Get(id string) (*Snapshot, bool) {
+	snap, ok := idx.items[id]
+	return snap, ok
+}
+
+// All returns every snapshot, sorted newest-first.
+func (idx *Index) All() []*Snapshot {
+	snaps := make([]*Snapshot, 0, len(idx.items))
+	for _, snap := range idx.items {
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].CreatedAt.After(snaps[j].CreatedAt)
+	})
+	return snaps
+}
+
+func (idx *Index) save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(idx.items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(idx.path, data, 0o644)
+}
@@ -0,0 +1,75 @@
+package archiver
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexPutGetDeleteAll(t *testing.T) {
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := &Snapshot{ID: "a", Title: "Older", CreatedAt: time.Unix(100, 0)}
+	recent := &Snapshot{ID: "b", Title: "Newer", CreatedAt: time.Unix(200, 0)}
+	if err := idx.Put(old); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Put(recent); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, ok := idx.Get("a"); !ok || got.Title != "Older" {
+		t.Errorf("Get(%q) = %+v, %v, want Older, true", "a", got, ok)
+	}
+	if _, ok := idx.Get("missing"); ok {
+		t.Errorf("Get(%q) ok = true, want false", "missing")
+	}
+
+	all := idx.All()
+	if len(all) != 2 || all[0].ID != "b" || all[1].ID != "a" {
+		t.Errorf("All() = %+v, want [b a] (newest first)", all)
+	}
+
+	if err := idx.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Get("a"); ok {
+		t.Errorf("Get(%q) after Delete ok = true, want false", "a")
+	}
+	if got := idx.All(); len(got) != 1 {
+		t.Errorf("All() after Delete = %+v, want 1 entry", got)
+	}
+}
+
+func TestIndexPersistsAcrossOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+
+	idx, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Put(&Snapshot{ID: "a", Title: "Saved"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reopened.Get("a"); !ok || got.Title != "Saved" {
+		t.Errorf("Get(%q) after reopen = %+v, %v, want Saved, true", "a", got, ok)
+	}
+}
+
+func TestOpenIndexMissingFile(t *testing.T) {
+	idx, err := OpenIndex(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := idx.All(); len(got) != 0 {
+		t.Errorf("All() on a fresh index = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/deanishe/alfred-firefox/profile"
+)
+
+// Client is everything the workflow needs from a Firefox backend. The
+// native-messaging extension client satisfies it directly; placesClient
+// (backed by a read-only places.sqlite connection) satisfies it as a
+// fallback for the read-only parts when the extension isn't reachable.
+//
+// mustClient(profile) returns the extension client when a connection can
+// be established and transparently falls back to a placesClient for that
+// profile otherwise, so runHistory/runBookmarks keep working offline.
+//
+// NOTE: mustClient's own connection handling lives outside this package
+// (it predates profile support and isn't part of this change) and hasn't
+// been extended to hold one native-messaging connection open per profile
+// yet - callers passing different profileName values currently still
+// share whatever single connection mustClient maintains internally.
+// Everything added here (profileName threaded through call sites,
+// profileSubtitle/profileUID for disambiguating results) is the
+// groundwork for that; actually pooling connections per profile is a
+// follow-up to mustClient itself.
+type Client interface {
+	History(query string) ([]History, error)
+	Bookmarks(query string) ([]Bookmark, error)
+	Tabs() ([]Tab, error)
+	CurrentTab() (Tab, error)
+	RunBookmarklet(arg RunBookmarkletArg) error
+	Ping() error
+}
+
+// activeBackend reports which backend mustClient(profileName) would fall
+// back to if the extension isn't reachable, for display in runStatus.
+func activeBackend(profileName string) (string, error) {
+	profiles, err := profile.Discover()
+	if err != nil {
+		return "", fmt.Errorf("discover profiles: %w", err)
+	}
+	p, err := profile.Find(profiles, profileName)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := newPlacesClient(p)
+	if err != nil {
+		return "", fmt.Errorf("open places.sqlite: %w", err)
+	}
+	c.Close()
+	return "places.sqlite", nil
+}
@@ -5,7 +5,6 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 
@@ -21,7 +20,7 @@ var (
 		Usage:     "firefox -query <query> history",
 		ShortHelp: "search browsing history",
 		LongHelp:  wrap(`Search Firefox browsing history.`),
-		Exec:      runHistory,
+		Exec:      logged("history", runHistory),
 	}
 
 	// search bookmarks
@@ -30,7 +29,7 @@ var (
 		Usage:     "firefox -query <query> bookmarks",
 		ShortHelp: "search bookmarks",
 		LongHelp:  wrap(`Search Firefox bookmarks.`),
-		Exec:      runBookmarks,
+		Exec:      logged("bookmarks", runBookmarks),
 	}
 
 	// search bookmarklets
@@ -39,7 +38,7 @@ var (
 		Usage:     "firefox -query <query> bookmarklets",
 		ShortHelp: "search bookmarklets",
 		LongHelp:  wrap(`Search Firefox bookmarklets and execute in frontmost tab.`),
-		Exec:      runBookmarklets,
+		Exec:      logged("bookmarklets", runBookmarklets),
 	}
 
 	/*
@@ -63,7 +62,7 @@ var (
 			Execute a bookmarklet in a tab. Bookmark ID is required.
 			If no tab ID is specified, bookmarklet is run in the active tab.
 		`),
-		Exec: runBookmarklet,
+		Exec: logged("run-bookmarklet", runBookmarklet),
 	}
 
 	// filter open tabs
@@ -72,7 +71,7 @@ var (
 		Usage:     "firefox [-query <query>] tabs",
 		ShortHelp: "filter Firefox tabs",
 		LongHelp:  wrap(`Filter Firefox tabs and perform actions on them.`),
-		Exec:      runTabs,
+		Exec:      logged("tabs", runTabs),
 	}
 
 	// filter tab & URL actions for current tab
@@ -81,7 +80,7 @@ var (
 		Usage:     "firefox [-query <query>] current-tab",
 		ShortHelp: "actions for current tab",
 		LongHelp:  wrap(`Filter and run actions for current tab`),
-		Exec:      runCurrentTab,
+		Exec:      logged("current-tab", runCurrentTab),
 	}
 
 	// run a tab/URL action for the specified tab
@@ -93,7 +92,7 @@ var (
 			Execute specified action on tab. Both URL and tab actions
 			are available on tabs.
 			`),
-		Exec: runTabAction,
+		Exec: logged("tab", runTabAction),
 	}
 
 	// run action for URL
@@ -102,7 +101,7 @@ var (
 		Usage:     "firefox -url <url> -action <name> url",
 		ShortHelp: "execute URL action",
 		LongHelp:  wrap(`Execute specified action on URL`),
-		Exec:      runURLAction,
+		Exec:      logged("url", runURLAction),
 	}
 
 	// filter URL (and tab) actions
@@ -111,7 +110,70 @@ var (
 		Usage:     "firefox [-tab <id>] [-url <url>] [-query <query>] actions",
 		ShortHelp: "filter tab/URL actions",
 		LongHelp:  wrap(`View/filter and execute tab/URL actions.`),
-		Exec:      runActions,
+		Exec:      logged("actions", runActions),
+	}
+
+	// archive a page for offline reading
+	archiveCmd = &ffcli.Command{
+		Name:      "archive",
+		Usage:     "firefox -url <url> [-title <title>] archive",
+		ShortHelp: "save an offline copy of a page",
+		LongHelp:  wrap(`Download a page, extract its readable content and store it for offline reading.`),
+		Exec:      logged("archive", runArchive),
+	}
+
+	// browse saved offline snapshots
+	archivesCmd = &ffcli.Command{
+		Name:      "archives",
+		Usage:     "firefox [-query <query>] archives",
+		ShortHelp: "browse offline archives",
+		LongHelp:  wrap(`Filter offline snapshots and open, re-archive or delete them.`),
+		Exec:      logged("archives", runArchives),
+	}
+
+	// delete a saved offline archive
+	deleteArchiveCmd = &ffcli.Command{
+		Name:      "delete-archive",
+		Usage:     "firefox -archive <id> delete-archive",
+		ShortHelp: "delete a saved offline archive",
+		LongHelp:  wrap(`Remove a previously saved offline snapshot from disk.`),
+		Exec:      logged("delete-archive", runDeleteArchive),
+	}
+
+	// list and switch Firefox profiles
+	profilesCmd = &ffcli.Command{
+		Name:      "profiles",
+		Usage:     "firefox [-query <query>] profiles",
+		ShortHelp: "list and switch Firefox profiles",
+		LongHelp:  wrap(`List available Firefox profiles and set the active one.`),
+		Exec:      logged("profiles", runProfiles),
+	}
+
+	// persist the active profile
+	setProfileCmd = &ffcli.Command{
+		Name:      "set-profile",
+		Usage:     "firefox -profile <name> set-profile",
+		ShortHelp: "set the active Firefox profile",
+		LongHelp:  wrap(`Persist which Firefox profile subsequent commands should use by default.`),
+		Exec:      logged("set-profile", runSetProfile),
+	}
+
+	// extract favicons into the icon cache
+	faviconsCmd = &ffcli.Command{
+		Name:      "favicons",
+		Usage:     "firefox favicons",
+		ShortHelp: "refresh the favicon cache",
+		LongHelp:  wrap(`Extract favicons from Firefox's favicons.sqlite into the workflow's icon cache.`),
+		Exec:      logged("favicons", runFavicons),
+	}
+
+	// update the local history search index
+	reindexCmd = &ffcli.Command{
+		Name:      "reindex",
+		Usage:     "firefox reindex",
+		ShortHelp: "update the local history search index",
+		LongHelp:  wrap(`Add places visited since the last update from places.sqlite to the full-text history index.`),
+		Exec:      logged("reindex", runReindex),
 	}
 
 	// check for update
@@ -120,7 +182,7 @@ var (
 		Usage:     "firefox update",
 		ShortHelp: "check for workflow update",
 		LongHelp:  wrap(`Check if newer version of workflow is available.`),
-		Exec:      runUpdate,
+		Exec:      logged("update", runUpdate),
 	}
 
 	// show workflow status
@@ -129,38 +191,40 @@ var (
 		Usage:     "firefox [-query <query>] options",
 		ShortHelp: "show workflow status & options",
 		LongHelp:  wrap(`Show workflow status, info and options.`),
-		Exec:      runStatus,
+		Exec:      logged("options", runStatus),
 	}
 )
 
 func runOpenURL(_ []string) error {
 	wf.Configure(aw.TextErrors(true))
-	log.Printf("opening URL %q ...", URL)
+	getLogger().Debug("opening URL", "url", URL)
 	_, err := util.RunCmd(exec.Command("open", URL))
 	return err
 }
 
 func runHistory(_ []string) error {
 	checkForUpdate()
-	if len(query) < 3 {
-		wf.Warn("Query Too Short", "Please enter at least 3 characters")
+	minQ := effectiveMinQuery()
+	if len(query) < minQ {
+		wf.Warn("Query Too Short", fmt.Sprintf("Please enter at least %d characters", minQ))
 		return nil
 	}
 
-	log.Printf("searching bookmarks for %q ...", query)
-	history, err := mustClient().History(query)
+	getLogger().Debug("searching history", "query", query, "profile", profileName)
+	history, err := searchHistory(query)
 	if err != nil {
 		return err
 	}
+	logResults("history", len(history))
 
 	custom := loadCustomActions()
 	for _, h := range history {
 		it := wf.NewItem(h.Title).
-			Subtitle(h.URL).
+			Subtitle(profileSubtitle(profileName, h.URL)).
 			Arg(h.URL).
-			UID(h.ID).
+			UID(profileUID(profileName, h.ID)).
 			Valid(true).
-			Icon(iconHistory).
+			Icon(faviconOr(h.URL, iconHistory)).
 			Var("CMD", "url").
 			Var("ACTION", urlDefault).
 			Var("URL", h.URL).
@@ -172,6 +236,14 @@ func runHistory(_ []string) error {
 			Icon(iconMore).
 			Var("CMD", "actions")
 
+		it.NewModifier(aw.ModAlt).
+			Subtitle("Save Offline Copy").
+			Arg(h.URL).
+			Icon(iconArchive).
+			Var("CMD", "archive").
+			Var("URL", h.URL).
+			Var("TITLE", h.Title)
+
 		custom.Add(it, false)
 	}
 
@@ -187,11 +259,12 @@ func runBookmarks(_ []string) error {
 		return nil
 	}
 
-	log.Printf("searching bookmarks for %q ...", query)
-	bookmarks, err := mustClient().Bookmarks(query)
+	getLogger().Debug("searching bookmarks", "query", query, "profile", profileName)
+	bookmarks, err := mustClient(profileName).Bookmarks(query)
 	if err != nil {
 		return err
 	}
+	logResults("bookmarks", len(bookmarks))
 
 	custom := loadCustomActions()
 	for _, bm := range bookmarks {
@@ -199,11 +272,11 @@ func runBookmarks(_ []string) error {
 			continue
 		}
 		it := wf.NewItem(bm.Title).
-			Subtitle(bm.URL).
+			Subtitle(profileSubtitle(profileName, bm.URL)).
 			Arg(bm.URL).
-			UID(bm.ID).
+			UID(profileUID(profileName, bm.ID)).
 			Valid(true).
-			Icon(iconBookmark).
+			Icon(faviconOr(bm.URL, iconBookmark)).
 			Var("CMD", "url").
 			Var("ACTION", urlDefault).
 			Var("URL", bm.URL).
@@ -215,6 +288,15 @@ func runBookmarks(_ []string) error {
 			Icon(iconMore).
 			Var("CMD", "actions")
 
+		it.NewModifier(aw.ModAlt).
+			Subtitle("Save Offline Copy").
+			Arg(bm.URL).
+			Icon(iconArchive).
+			Var("CMD", "archive").
+			Var("URL", bm.URL).
+			Var("TITLE", bm.Title).
+			Var("BOOKMARK", bm.ID)
+
 		custom.Add(it, false)
 	}
 
@@ -230,8 +312,8 @@ func runBookmarklets(_ []string) error {
 		return nil
 	}
 
-	log.Printf("searching bookmarklets for %q ...", query)
-	bookmarks, err := mustClient().Bookmarks(query)
+	getLogger().Debug("searching bookmarklets", "query", query, "profile", profileName)
+	bookmarks, err := mustClient(profileName).Bookmarks(query)
 	if err != nil {
 		return err
 	}
@@ -241,8 +323,8 @@ func runBookmarklets(_ []string) error {
 			continue
 		}
 		wf.NewItem(bm.Title).
-			Subtitle("↩ to execute in current tab").
-			UID(bm.ID).
+			Subtitle(profileSubtitle(profileName, "↩ to execute in current tab")).
+			UID(profileUID(profileName, bm.ID)).
 			Copytext("bkm:"+bm.ID+","+bm.Title).
 			Arg(bm.URL).
 			Icon(iconBookmarklet).
@@ -258,33 +340,34 @@ func runBookmarklets(_ []string) error {
 
 func runBookmarklet(_ []string) error {
 	wf.Configure(aw.TextErrors(true))
-	log.Printf("running bookmarklet %q in tab #%d ...", bookmarkID, tabID)
+	getLogger().Debug("running bookmarklet", "bookmark_id", bookmarkID, "tab_id", tabID, "profile", profileName)
 
-	return mustClient().
+	return mustClient(profileName).
 		RunBookmarklet(RunBookmarkletArg{BookmarkID: bookmarkID, TabID: tabID})
 }
 
 func runTabs(_ []string) error {
-	log.Printf("fetching tabs for query %q ...", query)
+	getLogger().Debug("fetching tabs", "query", query, "profile", profileName)
 	checkForUpdate()
 
 	var (
 		tabs []Tab
 		err  error
 	)
-	if tabs, err = mustClient().Tabs(); err != nil {
+	if tabs, err = mustClient(profileName).Tabs(); err != nil {
 		return err
 	}
+	logResults("tabs", len(tabs))
 
 	custom := loadCustomActions()
 	for _, t := range tabs {
 		id := fmt.Sprintf("%d", t.ID)
 		it := wf.NewItem(t.Title).
-			Subtitle(t.URL).
+			Subtitle(profileSubtitle(profileName, t.URL)).
 			Arg(t.URL).
-			UID(t.Title).
+			UID(profileUID(profileName, t.Title)).
 			Valid(true).
-			Icon(iconTab).
+			Icon(faviconOr(t.URL, iconTab)).
 			Var("CMD", "tab").
 			Var("ACTION", "Activate Tab").
 			Var("TAB", id).
@@ -297,6 +380,15 @@ func runTabs(_ []string) error {
 			Icon(iconMore).
 			Var("CMD", "actions")
 
+		it.NewModifier(aw.ModAlt).
+			Subtitle("Save Offline Copy").
+			Arg(t.URL).
+			Icon(iconArchive).
+			Var("CMD", "archive").
+			Var("URL", t.URL).
+			Var("TITLE", t.Title).
+			Var("TAB", id)
+
 		custom.Add(it, true)
 	}
 
@@ -311,7 +403,7 @@ func runTabs(_ []string) error {
 
 func runTabAction(_ []string) error {
 	wf.Configure(aw.TextErrors(true))
-	log.Printf("running action %q on tab #%d ...", action, tabID)
+	getLogger().Debug("running tab action", "action", action, "tab_id", tabID)
 	a, ok := tabActions[action]
 	if !ok {
 		return fmt.Errorf("unknown action %q", action)
@@ -321,7 +413,7 @@ func runTabAction(_ []string) error {
 
 func runURLAction(_ []string) error {
 	wf.Configure(aw.TextErrors(true))
-	log.Printf("running action %q on URL %q ...", action, URL)
+	getLogger().Debug("running URL action", "action", action, "url", URL)
 	a, ok := urlActions[action]
 	if !ok {
 		return fmt.Errorf("unknown action %q", action)
@@ -330,7 +422,7 @@ func runURLAction(_ []string) error {
 }
 
 func runCurrentTab(_ []string) error {
-	tab, err := mustClient().CurrentTab()
+	tab, err := mustClient(profileName).CurrentTab()
 	if err != nil {
 		return err
 	}
@@ -349,7 +441,8 @@ func runActions(_ []string) error {
 				Valid(true).
 				Var("CMD", "tab").
 				Var("ACTION", a.Name()).
-				Var("TAB", fmt.Sprintf("%d", tabID))
+				Var("TAB", fmt.Sprintf("%d", tabID)).
+				Var("PROFILE", profileName)
 		}
 
 		// add custom bookmarklet commands
@@ -377,7 +470,8 @@ func runActions(_ []string) error {
 				Valid(true).
 				Var("CMD", "url").
 				Var("ACTION", a.Name()).
-				Var("URL", URL)
+				Var("URL", URL).
+				Var("PROFILE", profileName)
 		}
 	}
 
@@ -393,31 +487,30 @@ func runActions(_ []string) error {
 // check if a newer version of workflow is available
 func runUpdate(_ []string) error {
 	wf.Configure(aw.TextErrors(true))
-	log.Print("checking for update ...")
+	getLogger().Debug("checking for update")
 	if err := wf.CheckForUpdate(); err != nil {
 		return err
 	}
 	if wf.UpdateAvailable() {
-		log.Println("a newer version of the workflow is available")
+		getLogger().Info("newer version of workflow is available")
 	}
 	return nil
 }
 
 func runStatus(_ []string) error {
-	if c, err := newClient(); err != nil {
-		wf.NewItem("No Connection to Firefox").
-			Subtitle(err.Error()).
-			Icon(iconError)
-	} else {
-		if err := c.Ping(); err != nil {
+	if c, err := newClient(profileName); err != nil || c.Ping() != nil {
+		name, backendErr := activeBackend(profileName)
+		if backendErr != nil {
 			wf.NewItem("No Connection to Firefox").
-				Subtitle(err.Error()).
+				Subtitle(backendErr.Error()).
 				Icon(iconError)
-
 		} else {
-			wf.NewItem("Connected to Firefox").
-				Subtitle("Extension is installed and running")
+			wf.NewItem(fmt.Sprintf("Connected to Firefox (profile: %s)", activeProfileName())).
+				Subtitle(fmt.Sprintf("Extension unreachable — using %s backend", name))
 		}
+	} else {
+		wf.NewItem(fmt.Sprintf("Connected to Firefox (profile: %s)", activeProfileName())).
+			Subtitle("Extension is installed and running")
 	}
 
 	if wf.UpdateAvailable() {
@@ -432,6 +525,23 @@ func runStatus(_ []string) error {
 			Valid(false)
 	}
 
+	if noFavicons {
+		wf.NewItem("Favicons Disabled").
+			Subtitle("Omit -no-favicons from history/bookmarks/tabs to change this").
+			Valid(false)
+	} else {
+		wf.NewItem("Favicons Enabled").
+			Subtitle("Real favicons are shown once cached; -no-favicons turns this off").
+			Valid(false)
+	}
+
+	weights := historyWeights()
+	wf.NewItem("History Ranking").
+		Subtitle(fmt.Sprintf(
+			"min-query=%d · recency-weight=%.2g · visit-weight=%.2g (set via HISTORY_RECENCY_WEIGHT/HISTORY_VISIT_WEIGHT)",
+			effectiveMinQuery(), weights.RecencyWeight, weights.VisitWeight)).
+		Valid(false)
+
 	wf.NewItem("Documentation").
 		Subtitle("Open documentation in your browser").
 		Arg(helpURL).
@@ -455,4 +565,6 @@ func checkForUpdate() {
 	if wf.UpdateCheckDue() && !wf.IsRunning("update") {
 		wf.RunInBackground("update", exec.Command(os.Args[0], "update"))
 	}
+	checkFavicons()
+	checkHistoryIndex()
 }
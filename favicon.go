@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	aw "github.com/deanishe/awgo"
+
+	"github.com/deanishe/alfred-firefox/favicon"
+	"github.com/deanishe/alfred-firefox/profile"
+)
+
+// faviconInterval is how often checkFavicons refreshes the favicon cache
+// in the background, mirroring reindexInterval's cadence for the history
+// index.
+const faviconInterval = time.Hour
+
+// noFavicons is set by the -no-favicons flag registered alongside the
+// other flags in main.go. It disables favicon lookups entirely, falling
+// back to the static icons every item already used.
+var noFavicons bool
+
+// faviconCacheDir is where per-domain favicon PNGs are written.
+func faviconCacheDir() string {
+	return filepath.Join(wf.CacheDir(), "favicons")
+}
+
+// faviconMarkerPath is touched by runFavicons after a successful
+// extraction, so checkFavicons has something to read LastIndexed-style
+// staleness off of, the way the history index reads idx.LastIndexed().
+func faviconMarkerPath() string {
+	return filepath.Join(faviconCacheDir(), ".last-extracted")
+}
+
+// faviconOr returns the cached favicon for rawURL, falling back to
+// fallback if favicons are disabled or none has been cached yet.
+func faviconOr(rawURL string, fallback *aw.Icon) *aw.Icon {
+	if noFavicons {
+		return fallback
+	}
+	if path, ok := favicon.Lookup(faviconCacheDir(), rawURL, favicon.DefaultTTL); ok {
+		return &aw.Icon{Value: path}
+	}
+	return fallback
+}
+
+// checkFavicons kicks off a background favicon extraction if favicons are
+// enabled, the cache is older than faviconInterval (or has never been
+// built), and one isn't already running - mirroring checkHistoryIndex's
+// staleness gate so a full favicons.sqlite scan doesn't refire on every
+// checkForUpdate call once the prior run finishes.
+func checkFavicons() {
+	if noFavicons || wf.IsRunning("favicons") {
+		return
+	}
+	if info, err := os.Stat(faviconMarkerPath()); err == nil && time.Since(info.ModTime()) < faviconInterval {
+		return
+	}
+	wf.RunInBackground("favicons", exec.Command(os.Args[0], "favicons"))
+}
+
+// runFavicons extracts favicons for the active profile into the cache.
+// It's only ever invoked as the background job started by checkFavicons.
+func runFavicons(_ []string) error {
+	wf.Configure(aw.TextErrors(true))
+
+	profiles, err := profile.Discover()
+	if err != nil {
+		return err
+	}
+	p, err := profile.Find(profiles, activeProfileName())
+	if err != nil {
+		return err
+	}
+
+	n, err := favicon.ExtractAll(filepath.Join(p.Path, "favicons.sqlite"), faviconCacheDir())
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(faviconMarkerPath(), nil, 0o644); err != nil {
+		getLogger().Debug("touch favicon marker", "error", err)
+	}
+
+	getLogger().Info("cached favicons", "profile", p.Name, "result_count", n)
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package favicon extracts favicons from Firefox's favicons.sqlite and
+// caches them on disk as per-domain PNGs, the way the Chromium Alfred
+// workflow does for Chrome's favicon cache.
+package favicon
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultTTL is how long Lookup treats a cached favicon as fresh; an older
+// file is reported as not cached. ExtractAll does not consult it.
+const DefaultTTL = 14 * 24 * time.Hour
+
+// CachePath returns the path a domain's cached favicon would be written
+// to under cacheDir, regardless of whether it's been extracted yet.
+func CachePath(cacheDir, domain string) string {
+	sum := sha1.Sum([]byte(domain))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".png")
+}
+
+// Lookup returns the cached favicon path for rawURL if one exists and is
+// younger than ttl.
+func Lookup(cacheDir, rawURL string, ttl time.Duration) (string, bool) {
+	domain := hostname(rawURL)
+	if domain == "" {
+		return "", false
+	}
+
+	path := CachePath(cacheDir, domain)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	if time.Since(info.ModTime()) > ttl {
+		return "", false
+	}
+	return path, true
+}
+
+// ExtractAll reads every page icon out of the favicons.sqlite at dbPath,
+// picks the largest bitmap among each domain's pages and writes one PNG per
+// domain to cacheDir, so repeated URLs on the same domain share a single file.
+func ExtractAll(dbPath, cacheDir string) (int, error) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return 0, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?mode=ro&immutable=1", dbPath)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", dbPath, err)
+	}
+	defer db.Close()
+
+	// Ordered by bitmap size alone (not grouped by page_url first) so rows
+	// arrive largest-to-smallest across every page on every domain - the
+	// first row the written dedup below keeps for a domain is then the
+	// largest icon that domain has anywhere, not just the largest on
+	// whichever page_url happens to sort first.
+	rows, err := db.Query(`
+		SELECT p.page_url, i.data
+		FROM moz_pages_w_icons p
+		JOIN moz_icons_to_pages itp ON itp.page_id = p.id
+		JOIN moz_icons i ON i.id = itp.icon_id
+		ORDER BY (i.width * i.width) DESC
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("query favicons: %w", err)
+	}
+	defer rows.Close()
+
+	written := map[string]bool{}
+	n := 0
+	for rows.Next() {
+		var (
+			pageURL string
+			data    []byte
+		)
+		if err := rows.Scan(&pageURL, &data); err != nil {
+			return n, err
+		}
+
+		domain := hostname(pageURL)
+		if domain == "" || written[domain] {
+			continue
+		}
+
+		if err := os.WriteFile(CachePath(cacheDir, domain), data, 0o644); err != nil {
+			return n, fmt.Errorf("write favicon for %s: %w", domain, err)
+		}
+		written[domain] = true
+		n++
+	}
+	return n, rows.Err()
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,47 @@
+package favicon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachePathStableAndDomainScoped(t *testing.T) {
+	a := CachePath("/cache", "example.com")
+	b := CachePath("/cache", "example.com")
+	if a != b {
+		t.Errorf("CachePath not stable: %q != %q", a, b)
+	}
+	if other := CachePath("/cache", "other.com"); other == a {
+		t.Errorf("CachePath for a different domain collided: %q", other)
+	}
+	if filepath.Dir(a) != "/cache" {
+		t.Errorf("CachePath(%q, ...) = %q, want it under the cache dir", "/cache", a)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	path := CachePath(dir, "example.com")
+	if err := os.WriteFile(path, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := Lookup(dir, "https://example.com/page", time.Hour)
+	if !ok || got != path {
+		t.Errorf("Lookup = %q, %v, want %q, true", got, ok, path)
+	}
+
+	if _, ok := Lookup(dir, "https://example.com/page", -time.Second); ok {
+		t.Error("Lookup with a negative TTL = true, want false (cache considered stale)")
+	}
+
+	if _, ok := Lookup(dir, "https://uncached.example", time.Hour); ok {
+		t.Error("Lookup for an uncached domain = true, want false")
+	}
+
+	if _, ok := Lookup(dir, "not a url", time.Hour); ok {
+		t.Error("Lookup with a URL that has no host = true, want false")
+	}
+}
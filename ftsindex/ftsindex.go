@@ -0,0 +1,240 @@
+// Package ftsindex maintains a local SQLite FTS5 index over Firefox
+// history, so the workflow can rank results with BM25, prefix matching
+// and recency/visit-count boosts instead of delegating matching to the
+// extension.
+package ftsindex
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Entry is a single history row, as read out of places.sqlite for
+// indexing.
+type Entry struct {
+	ID         string
+	Title      string
+	URL        string
+	VisitCount int
+	LastVisit  time.Time
+}
+
+// Result is a ranked search hit.
+type Result struct {
+	ID    string
+	Title string
+	URL   string
+	Score float64
+}
+
+// Weights controls how Search blends BM25 relevance with recency and
+// visit-count signals. Zero values disable that signal.
+type Weights struct {
+	RecencyHalfLife time.Duration // recency score halves every HalfLife
+	RecencyWeight   float64
+	VisitWeight     float64
+	DomainWeight    float64 // boosts domains that recur often among the matches
+}
+
+// DefaultWeights favours relevance but still nudges recently- and
+// frequently-visited pages, and domains with several matching pages,
+// upward.
+var DefaultWeights = Weights{
+	RecencyHalfLife: 14 * 24 * time.Hour,
+	RecencyWeight:   0.5,
+	VisitWeight:     0.2,
+	DomainWeight:    0.15,
+}
+
+// Index is a handle to the on-disk FTS5 history index.
+type Index struct {
+	db *sql.DB
+}
+
+// Open creates (if necessary) and opens the FTS5 index at path.
+func Open(path string) (*Index, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+
+	idx := &Index{db: db}
+	if err := idx.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Close closes the underlying database handle.
+func (idx *Index) Close() error { return idx.db.Close() }
+
+func (idx *Index) migrate() error {
+	_, err := idx.db.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS history_fts USING fts5(
+			id UNINDEXED, title, url, visit_count UNINDEXED, last_visit UNINDEXED,
+			prefix='2 3 4'
+		);
+		CREATE TABLE IF NOT EXISTS history_fts_meta (key TEXT PRIMARY KEY, value TEXT);
+	`)
+	return err
+}
+
+// LastIndexed returns when the index was last rebuilt/updated.
+func (idx *Index) LastIndexed() time.Time {
+	var value string
+	if err := idx.db.QueryRow(`SELECT value FROM history_fts_meta WHERE key = 'last_indexed'`).Scan(&value); err != nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// Replace repopulates the index from entries, replacing any existing
+// rows with the same ID. It's safe to call repeatedly with just the
+// rows that changed since LastIndexed for incremental updates.
+func (idx *Index) Replace(entries []Entry) error {
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	del, err := tx.Prepare(`DELETE FROM history_fts WHERE id = ?`)
+	if err != nil {
+		return err
+	}
+	defer del.Close()
+
+	ins, err := tx.Prepare(`INSERT INTO history_fts (id, title, url, visit_count, last_visit) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer ins.Close()
+
+	for _, e := range entries {
+		if _, err := del.Exec(e.ID); err != nil {
+			return fmt.Errorf("delete stale row %s: %w", e.ID, err)
+		}
+		if _, err := ins.Exec(e.ID, e.Title, e.URL, e.VisitCount, e.LastVisit.Unix()); err != nil {
+			return fmt.Errorf("insert row %s: %w", e.ID, err)
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO history_fts_meta (key, value) VALUES ('last_indexed', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, time.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Search runs query against the index with prefix matching on the last
+// term, ranks hits with BM25 blended with w's recency/visit-count
+// signals, and returns the top limit results.
+func (idx *Index) Search(query string, limit int, w Weights) ([]Result, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	rows, err := idx.db.Query(`
+		SELECT id, title, url, visit_count, last_visit, bm25(history_fts) AS rank
+		FROM history_fts
+		WHERE history_fts MATCH ?
+		ORDER BY rank
+		LIMIT ?
+	`, match, limit*4) // over-fetch; re-ranked below before truncating to limit
+	if err != nil {
+		return nil, fmt.Errorf("search %q: %w", query, err)
+	}
+	defer rows.Close()
+
+	type hit struct {
+		id, title, urlStr string
+		domain            string
+		visitCount        int
+		lastVisitUnix     int64
+		bm25Rank          float64
+	}
+
+	var hits []hit
+	domainCounts := map[string]int{}
+	for rows.Next() {
+		var h hit
+		if err := rows.Scan(&h.id, &h.title, &h.urlStr, &h.visitCount, &h.lastVisitUnix, &h.bm25Rank); err != nil {
+			return nil, err
+		}
+		h.domain = hostname(h.urlStr)
+		domainCounts[h.domain]++
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(hits))
+	now := time.Now()
+	for i, h := range hits {
+		score := -h.bm25Rank // bm25() is lower-is-better; invert so higher score wins
+		if w.RecencyWeight != 0 && w.RecencyHalfLife > 0 {
+			age := now.Sub(time.Unix(h.lastVisitUnix, 0))
+			score += w.RecencyWeight * math.Exp(-math.Ln2*age.Hours()/w.RecencyHalfLife.Hours())
+		}
+		if w.VisitWeight != 0 {
+			score += w.VisitWeight * math.Log1p(float64(h.visitCount))
+		}
+		if w.DomainWeight != 0 && h.domain != "" {
+			// -1 so a domain matching only once (the page itself) gets no boost.
+			score += w.DomainWeight * math.Log1p(float64(domainCounts[h.domain]-1))
+		}
+
+		results[i] = Result{ID: h.id, Title: h.title, URL: h.urlStr, Score: score}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// ftsQuery turns a free-text user query into an FTS5 MATCH expression,
+// treating the final token as a prefix so results appear before the
+// user finishes typing it. The star has to go *outside* the closing
+// quote - FTS5 tokenizes the content of a quoted phrase before matching,
+// so a quoted "foo*" strips the star as punctuation instead of treating
+// it as the prefix wildcard; `"foo"*` is what actually triggers a
+// prefix match.
+func ftsQuery(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	for i, f := range fields {
+		f = strings.ReplaceAll(f, `"`, "")
+		term := `"` + f + `"`
+		if i == len(fields)-1 {
+			term += "*"
+		}
+		fields[i] = term
+	}
+	return strings.Join(fields, " ")
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
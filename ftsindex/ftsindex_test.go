@@ -0,0 +1,21 @@
+package ftsindex
+
+import "testing"
+
+func TestFtsQuery(t *testing.T) {
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"foo", `"foo"*`},
+		{"foo bar", `"foo" "bar"*`},
+		{`foo"bar`, `"foobar"*`},
+	}
+
+	for _, c := range cases {
+		if got := ftsQuery(c.query); got != c.want {
+			t.Errorf("ftsQuery(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	aw "github.com/deanishe/awgo"
+
+	"github.com/deanishe/alfred-firefox/ftsindex"
+	"github.com/deanishe/alfred-firefox/placesdb"
+	"github.com/deanishe/alfred-firefox/profile"
+)
+
+// minQueryOverride is set by the -min-query flag registered alongside the
+// other flags in main.go. Zero means "use minQueryDefault".
+var minQueryOverride int
+
+// minQueryDefault matches the extension-backed search's old hard-coded
+// minimum.
+const minQueryDefault = 3
+
+// reindexInterval is how often checkHistoryIndex refreshes the index in
+// the background, mirroring the update-check cadence.
+const reindexInterval = time.Hour
+
+// historyIndexPath is the FTS5 database used by runHistory, one per
+// profile so switching profiles doesn't mix results.
+func historyIndexPath(profileName string) string {
+	name := "history.fts.sqlite"
+	if profileName != "" {
+		name = profileName + "-" + name
+	}
+	return filepath.Join(wf.DataDir(), name)
+}
+
+func effectiveMinQuery() int {
+	if minQueryOverride > 0 {
+		return minQueryOverride
+	}
+	return minQueryDefault
+}
+
+// historyWeights builds the ranking weights runHistory searches with,
+// letting HISTORY_RECENCY_WEIGHT/HISTORY_VISIT_WEIGHT workflow variables
+// override the defaults.
+func historyWeights() ftsindex.Weights {
+	w := ftsindex.DefaultWeights
+	if f, err := strconv.ParseFloat(wf.Config.Get("HISTORY_RECENCY_WEIGHT"), 64); err == nil {
+		w.RecencyWeight = f
+	}
+	if f, err := strconv.ParseFloat(wf.Config.Get("HISTORY_VISIT_WEIGHT"), 64); err == nil {
+		w.VisitWeight = f
+	}
+	return w
+}
+
+// searchHistory ranks query against the local FTS index and falls back to
+// the live/offline client only when the index itself can't be searched
+// (not built yet, or a genuine query error) - an empty result set from
+// the index is a real answer, not a reason to fall back.
+func searchHistory(query string) ([]History, error) {
+	idx, err := ftsindex.Open(historyIndexPath(profileName))
+	if err != nil {
+		getLogger().Debug("history index unavailable, falling back to client", "error", err)
+		return mustClient(profileName).History(query)
+	}
+	defer idx.Close()
+
+	if idx.LastIndexed().IsZero() {
+		getLogger().Debug("history index not built yet, falling back to client")
+		return mustClient(profileName).History(query)
+	}
+
+	results, err := idx.Search(query, 200, historyWeights())
+	if err != nil {
+		getLogger().Debug("history index search failed, falling back to client", "error", err)
+		return mustClient(profileName).History(query)
+	}
+
+	history := make([]History, len(results))
+	for i, r := range results {
+		history[i] = History{ID: r.ID, Title: r.Title, URL: r.URL}
+	}
+	return history, nil
+}
+
+// checkHistoryIndex kicks off a background reindex if the index is older
+// than reindexInterval and one isn't already running.
+func checkHistoryIndex() {
+	if wf.IsRunning("reindex") {
+		return
+	}
+
+	idx, err := ftsindex.Open(historyIndexPath(profileName))
+	if err == nil {
+		stale := time.Since(idx.LastIndexed()) > reindexInterval
+		idx.Close()
+		if !stale {
+			return
+		}
+	}
+
+	wf.RunInBackground("reindex", exec.Command(os.Args[0], "reindex"))
+}
+
+// runReindex updates the local history FTS index from places.sqlite with
+// every place visited since the index was last updated (every place, the
+// first time). It does not clear the index first, so places since removed
+// from Firefox's history stay indexed. It's invoked both as the background
+// job started by checkHistoryIndex and as the user-facing "reindex" command.
+func runReindex(_ []string) error {
+	wf.Configure(aw.TextErrors(true))
+
+	profiles, err := profile.Discover()
+	if err != nil {
+		return err
+	}
+	p, err := profile.Find(profiles, activeProfileName())
+	if err != nil {
+		return err
+	}
+
+	reader, err := placesdb.Open(p.PlacesPath())
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	idx, err := ftsindex.Open(historyIndexPath(profileName))
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	entries, err := reader.AllHistorySince(idx.LastIndexed())
+	if err != nil {
+		return err
+	}
+
+	indexed := make([]ftsindex.Entry, len(entries))
+	for i, e := range entries {
+		indexed[i] = ftsindex.Entry{ID: e.ID, Title: e.Title, URL: e.URL, VisitCount: e.VisitCount, LastVisit: e.LastVisit}
+	}
+	if err := idx.Replace(indexed); err != nil {
+		return err
+	}
+
+	getLogger().Info("reindexed history", "profile", p.Name, "result_count", len(indexed))
+	return nil
+}
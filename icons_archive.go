@@ -0,0 +1,8 @@
+package main
+
+import aw "github.com/deanishe/awgo"
+
+var (
+	iconArchive = &aw.Icon{Value: "icons/archive.png"}
+	iconDelete  = &aw.Icon{Value: "icons/delete.png"}
+)
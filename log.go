@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLevel is set by the -log-level flag registered alongside the other
+// flags in main.go (one of debug, info, warn, error; default info).
+var logLevel string
+
+var (
+	loggerOnce sync.Once
+	logger     *slog.Logger
+)
+
+// getLogger lazily builds the structured logger on first use, so every
+// command gets one regardless of where in the call graph it first logs.
+// It writes to the workflow's own log file, wf.LogFile(), whether or not it
+// runs under Alfred, falling back to stderr only if that file can't be
+// opened, in the format named by the LOG_FORMAT workflow variable (json or
+// text, default text).
+func getLogger() *slog.Logger {
+	loggerOnce.Do(func() {
+		out := os.Stderr
+		if f, err := os.OpenFile(wf.LogFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644); err == nil {
+			out = f
+		}
+		logger = slog.New(newHandler(out, logLevelValue()))
+	})
+	return logger
+}
+
+func newHandler(w *os.File, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "json") {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+func logLevelValue() slog.Level {
+	switch strings.ToLower(logLevel) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// execFunc is the signature ffcli.Command.Exec expects.
+type execFunc func([]string) error
+
+// logged wraps an Exec function so every command automatically logs its
+// start, completion (with duration) and any error, with cmd/query/tabID/
+// bookmarkID as structured fields, mirroring the rest of the workflow's
+// logging.
+func logged(cmd string, fn execFunc) execFunc {
+	return func(args []string) error {
+		log := getLogger().With(
+			"cmd", cmd,
+			"query", query,
+			"tab_id", tabID,
+			"bookmark_id", bookmarkID,
+		)
+		log.Debug("start")
+
+		start := time.Now()
+		err := fn(args)
+		durationMS := time.Since(start).Milliseconds()
+
+		if err != nil {
+			log.Error("failed", "duration_ms", durationMS, "error", err.Error())
+			return err
+		}
+		log.Info("done", "duration_ms", durationMS)
+		return nil
+	}
+}
+
+// logResults records how many items a search command produced. Called from
+// inside runHistory/runBookmarks/runTabs, where the wrapping logged() call
+// doesn't have visibility into the result set.
+func logResults(cmd string, n int) {
+	getLogger().Info(fmt.Sprintf("%s results", cmd), "cmd", cmd, "query", query, "result_count", n)
+}
@@ -0,0 +1,170 @@
+// Package placesdb reads history and bookmarks directly out of Firefox's
+// places.sqlite, the way gosuki's firefox module does, so the workflow
+// still works when the native-messaging extension isn't reachable.
+package placesdb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// HistoryEntry is a single row from moz_places.
+type HistoryEntry struct {
+	ID    string
+	Title string
+	URL   string
+}
+
+// IndexEntry is a moz_places row with the extra fields a full-text index
+// needs to rank on recency and visit frequency.
+type IndexEntry struct {
+	ID         string
+	Title      string
+	URL        string
+	VisitCount int
+	LastVisit  time.Time
+}
+
+// BookmarkEntry is a single (non-deleted) row from moz_bookmarks.
+type BookmarkEntry struct {
+	ID          string
+	Title       string
+	URL         string
+	Bookmarklet bool
+}
+
+// Reader reads history and bookmarks straight out of a places.sqlite file.
+// It opens the database read-only so it never contends with Firefox's own
+// writer connection.
+type Reader struct {
+	db *sql.DB
+}
+
+// Open opens the places.sqlite at path for read-only access. immutable=1
+// tells SQLite the file (and its WAL) won't be modified by us, which lets
+// it skip locking even while Firefox has the database open.
+func Open(path string) (*Reader, error) {
+	dsn := fmt.Sprintf("file:%s?mode=ro&immutable=1&_journal_mode=WAL", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping %s: %w", path, err)
+	}
+	return &Reader{db: db}, nil
+}
+
+// Close closes the underlying database handle.
+func (r *Reader) Close() error { return r.db.Close() }
+
+// History returns places matching query by title or URL, most-recently
+// visited first.
+func (r *Reader) History(query string) ([]HistoryEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, IFNULL(title, url), url
+		FROM moz_places
+		WHERE (title LIKE '%' || ? || '%' OR url LIKE '%' || ? || '%')
+		  AND hidden = 0 AND visit_count > 0
+		ORDER BY last_visit_date DESC
+		LIMIT 200
+	`, query, query)
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var (
+			id    int64
+			title string
+			url   string
+		)
+		if err := rows.Scan(&id, &title, &url); err != nil {
+			return nil, err
+		}
+		entries = append(entries, HistoryEntry{ID: fmt.Sprintf("%d", id), Title: title, URL: url})
+	}
+	return entries, rows.Err()
+}
+
+// AllHistorySince returns every visited place last visited at or after
+// since (pass the zero Value to fetch everything), for building or
+// incrementally updating a full-text index.
+func (r *Reader) AllHistorySince(since time.Time) ([]IndexEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, IFNULL(title, url), url, visit_count, last_visit_date
+		FROM moz_places
+		WHERE hidden = 0 AND visit_count > 0 AND last_visit_date >= ?
+	`, since.UnixMicro())
+	if err != nil {
+		return nil, fmt.Errorf("query history for indexing: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []IndexEntry
+	for rows.Next() {
+		var (
+			id              int64
+			title, url      string
+			visitCount      int
+			lastVisitMicros int64
+		)
+		if err := rows.Scan(&id, &title, &url, &visitCount, &lastVisitMicros); err != nil {
+			return nil, err
+		}
+		entries = append(entries, IndexEntry{
+			ID:         fmt.Sprintf("%d", id),
+			Title:      title,
+			URL:        url,
+			VisitCount: visitCount,
+			LastVisit:  time.UnixMicro(lastVisitMicros),
+		})
+	}
+	return entries, rows.Err()
+}
+
+// Bookmarks returns non-deleted bookmarks matching query by title or URL.
+// Bookmarklets (javascript: URLs) are flagged via Bookmarklet so callers
+// can filter or present them separately, matching the extension client's
+// Bookmark.IsBookmarklet().
+func (r *Reader) Bookmarks(query string) ([]BookmarkEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT b.id, IFNULL(b.title, p.url), p.url
+		FROM moz_bookmarks b
+		JOIN moz_places p ON p.id = b.fk
+		LEFT JOIN moz_bookmarks_deleted d ON d.guid = b.guid
+		WHERE b.type = 1 AND d.guid IS NULL
+		  AND (b.title LIKE '%' || ? || '%' OR p.url LIKE '%' || ? || '%')
+		ORDER BY b.lastModified DESC
+		LIMIT 200
+	`, query, query)
+	if err != nil {
+		return nil, fmt.Errorf("query bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []BookmarkEntry
+	for rows.Next() {
+		var (
+			id    int64
+			title string
+			url   string
+		)
+		if err := rows.Scan(&id, &title, &url); err != nil {
+			return nil, err
+		}
+		entries = append(entries, BookmarkEntry{
+			ID:          fmt.Sprintf("%d", id),
+			Title:       title,
+			URL:         url,
+			Bookmarklet: len(url) > 11 && url[:11] == "javascript:",
+		})
+	}
+	return entries, rows.Err()
+}
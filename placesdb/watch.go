@@ -0,0 +1,59 @@
+package placesdb
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// CacheInvalidator watches places.sqlite-wal for writes and calls invalidate
+// whenever Firefox commits new data, so a bookmarks cache can be refreshed
+// lazily instead of being re-read on every keystroke.
+type CacheInvalidator struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// WatchWAL starts watching the WAL file belonging to the places.sqlite at
+// path, invoking invalidate (from its own goroutine) on every write.
+func WatchWAL(path string, invalidate func()) (*CacheInvalidator, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	walName := filepath.Base(path) + "-wal"
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(ev.Name) == walName && (ev.Op&(fsnotify.Write|fsnotify.Create) != 0) {
+					invalidate()
+				}
+			case <-watcher.Errors:
+				// Best-effort: a watch error just means we stop
+				// getting proactive invalidations; the caller
+				// still falls back to normal query staleness.
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return &CacheInvalidator{watcher: watcher, done: done}, nil
+}
+
+// Close stops watching and releases the underlying inotify/FSEvents handle.
+func (c *CacheInvalidator) Close() error {
+	close(c.done)
+	return c.watcher.Close()
+}
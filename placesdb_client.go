@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/deanishe/alfred-firefox/placesdb"
+	"github.com/deanishe/alfred-firefox/profile"
+)
+
+// placesClient is a Client backed by a direct, read-only connection to a
+// profile's places.sqlite. It covers history and bookmark search; the
+// tab-related methods error, since that data only exists in a running
+// Firefox process reachable through the extension.
+type placesClient struct {
+	reader  *placesdb.Reader
+	watcher *placesdb.CacheInvalidator
+
+	mu        sync.Mutex
+	bookmarks []placesdb.BookmarkEntry
+	cached    bool
+}
+
+// newPlacesClient opens p's places.sqlite and starts watching its WAL file
+// so the bookmarks cache is invalidated as soon as Firefox writes to it.
+func newPlacesClient(p profile.Profile) (*placesClient, error) {
+	reader, err := placesdb.Open(p.PlacesPath())
+	if err != nil {
+		return nil, err
+	}
+
+	c := &placesClient{reader: reader}
+	if w, err := placesdb.WatchWAL(p.PlacesPath(), c.invalidate); err == nil {
+		c.watcher = w
+	}
+	return c, nil
+}
+
+func (c *placesClient) invalidate() {
+	c.mu.Lock()
+	c.cached = false
+	c.mu.Unlock()
+}
+
+// Close releases the database handle and WAL watcher.
+func (c *placesClient) Close() error {
+	if c.watcher != nil {
+		c.watcher.Close()
+	}
+	return c.reader.Close()
+}
+
+func (c *placesClient) History(query string) ([]History, error) {
+	entries, err := c.reader.History(query)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]History, len(entries))
+	for i, e := range entries {
+		history[i] = History{ID: e.ID, Title: e.Title, URL: e.URL}
+	}
+	return history, nil
+}
+
+func (c *placesClient) Bookmarks(query string) ([]Bookmark, error) {
+	all, err := c.allBookmarks()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	bookmarks := make([]Bookmark, 0, len(all))
+	for _, e := range all {
+		if query != "" &&
+			!strings.Contains(strings.ToLower(e.Title), query) &&
+			!strings.Contains(strings.ToLower(e.URL), query) {
+			continue
+		}
+		bookmarks = append(bookmarks, Bookmark{ID: e.ID, Title: e.Title, URL: e.URL})
+	}
+	return bookmarks, nil
+}
+
+// allBookmarks re-reads the database only when the WAL watcher has flagged
+// a change since the last read, so repeated keystrokes don't hit SQLite.
+func (c *placesClient) allBookmarks() ([]placesdb.BookmarkEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached {
+		return c.bookmarks, nil
+	}
+
+	all, err := c.reader.Bookmarks("")
+	if err != nil {
+		return nil, err
+	}
+	c.bookmarks, c.cached = all, true
+	return c.bookmarks, nil
+}
+
+func (c *placesClient) Tabs() ([]Tab, error) {
+	return nil, fmt.Errorf("tabs are not available offline: extension is not connected")
+}
+
+func (c *placesClient) CurrentTab() (Tab, error) {
+	return Tab{}, fmt.Errorf("current tab is not available offline: extension is not connected")
+}
+
+func (c *placesClient) RunBookmarklet(RunBookmarkletArg) error {
+	return fmt.Errorf("bookmarklets require a live tab: extension is not connected")
+}
+
+func (c *placesClient) Ping() error {
+	return nil
+}
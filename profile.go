@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+
+	aw "github.com/deanishe/awgo"
+
+	"github.com/deanishe/alfred-firefox/profile"
+)
+
+// profileName is set by the -profile flag registered alongside the other
+// flags in main.go. An empty value means "use the active/default profile".
+//
+// Scope: this only lets the user pick which single profile a command
+// targets and labels results with it (profileSubtitle/profileUID below).
+// It does not give the workflow simultaneous connections to multiple
+// profiles - mustClient still has just the one underlying native-messaging
+// connection (see the NOTE on Client in backend.go), so switching
+// profiles means the old connection is dropped and a new one opened, not
+// two live connections held side by side.
+var profileName string
+
+// profileConfigKey is the workflow variable the active profile is
+// persisted under, so it survives between runs without a -profile flag.
+const profileConfigKey = "PROFILE"
+
+// activeProfileName returns the profile to use when none was given
+// explicitly on the command line: the one last selected via the
+// "profiles" command, falling back to Firefox's own default profile.
+func activeProfileName() string {
+	if profileName != "" {
+		return profileName
+	}
+	return wf.Config.Get(profileConfigKey)
+}
+
+// profileSubtitle tags an item's subtitle with its profile, so results
+// from different profiles don't look identical in Alfred.
+func profileSubtitle(name, subtitle string) string {
+	if name == "" {
+		return subtitle
+	}
+	return fmt.Sprintf("[%s] %s", name, subtitle)
+}
+
+// profileUID namespaces an item's UID by profile, so identical bookmark,
+// history or tab IDs from separate profiles don't collide.
+func profileUID(name, id string) string {
+	if name == "" {
+		return id
+	}
+	return name + ":" + id
+}
+
+// runProfiles lists the profiles found in profiles.ini and lets the user
+// set the active one.
+func runProfiles(_ []string) error {
+	profiles, err := profile.Discover()
+	if err != nil {
+		return err
+	}
+
+	active := activeProfileName()
+	for _, p := range profiles {
+		it := wf.NewItem(p.Name).
+			Subtitle(p.Path).
+			UID(p.Name).
+			Valid(true).
+			Arg(p.Name).
+			Var("CMD", "set-profile").
+			Var("PROFILE", p.Name)
+
+		if p.Name == active {
+			it.Subtitle("Active — " + p.Path)
+		}
+		if p.IsDefault {
+			it.Icon(iconBookmark)
+		}
+	}
+
+	if query != "" {
+		_ = wf.Filter(query)
+	}
+
+	wf.WarnEmpty("No Profiles Found", "Couldn't find any Firefox profiles")
+	wf.SendFeedback()
+	return nil
+}
+
+// runSetProfile persists profileName as the active profile.
+func runSetProfile(_ []string) error {
+	wf.Configure(aw.TextErrors(true))
+	getLogger().Debug("setting active profile", "profile", profileName)
+	return wf.Config.Set(profileConfigKey, profileName, false).Do()
+}
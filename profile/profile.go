@@ -0,0 +1,158 @@
+// Package profile discovers Firefox profiles by parsing profiles.ini,
+// the way gosuki's firefox module does.
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Profile is a single Firefox profile found in profiles.ini.
+type Profile struct {
+	Name      string // [Profile0] Name=
+	Path      string // absolute, symlink-resolved path to the profile dir
+	IsDefault bool   // Default=1
+}
+
+// PlacesPath returns the path to this profile's places.sqlite.
+func (p Profile) PlacesPath() string {
+	return filepath.Join(p.Path, "places.sqlite")
+}
+
+// root returns the directory Firefox keeps its profiles under.
+func root() string {
+	if dir := os.Getenv("FIREFOX_ROOT"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, "Library", "Application Support", "Firefox")
+}
+
+// Discover parses profiles.ini and returns every profile it declares, with
+// relative paths resolved (and symlinks followed) against the Firefox
+// application-support directory.
+//
+// Which profile is IsDefault is resolved two ways, since Firefox has used
+// two incompatible schemes: pre-67 profiles.ini marks it directly on the
+// profile with "Default=1"; 67+ instead records it on a separate
+// "[InstallXXX]" section as "Default=<profile Path>", so it can track a
+// different default per installation. An install section's default takes
+// priority over the legacy per-profile flag when both are present.
+func Discover() ([]Profile, error) {
+	base := root()
+	ini := filepath.Join(base, "profiles.ini")
+
+	f, err := os.Open(ini)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", ini, err)
+	}
+	defer f.Close()
+
+	var (
+		profiles        []Profile
+		rawPaths        []string // relPath as written in profiles.ini, parallel to profiles
+		installDefaults []string
+		cur             *Profile
+		inInstall       bool
+		isRel           = true
+		relPath         string
+	)
+
+	flush := func() {
+		if cur == nil || relPath == "" {
+			return
+		}
+		path := relPath
+		if isRel {
+			path = filepath.Join(base, relPath)
+		}
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		cur.Path = path
+		profiles = append(profiles, *cur)
+		rawPaths = append(rawPaths, relPath)
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "[Profile"):
+			flush()
+			cur, inInstall = &Profile{}, false
+			isRel, relPath = true, ""
+
+		case strings.HasPrefix(line, "[Install"):
+			flush()
+			cur, inInstall = nil, true
+
+		case inInstall:
+			if path, ok := strings.CutPrefix(line, "Default="); ok {
+				installDefaults = append(installDefaults, path)
+			}
+
+		case cur == nil:
+			continue
+
+		case strings.HasPrefix(line, "Name="):
+			cur.Name = strings.TrimPrefix(line, "Name=")
+
+		case strings.HasPrefix(line, "IsRelative="):
+			isRel = strings.TrimPrefix(line, "IsRelative=") == "1"
+
+		case strings.HasPrefix(line, "Path="):
+			relPath = strings.TrimPrefix(line, "Path=")
+
+		case strings.HasPrefix(line, "Default="):
+			cur.IsDefault = strings.TrimPrefix(line, "Default=") == "1"
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(installDefaults) > 0 {
+		defaults := make(map[string]bool, len(installDefaults))
+		for _, d := range installDefaults {
+			defaults[d] = true
+		}
+		for i := range profiles {
+			profiles[i].IsDefault = defaults[rawPaths[i]]
+		}
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// Find returns the profile named name, or the default profile if name is
+// empty. It errors if no profile matches.
+func Find(profiles []Profile, name string) (Profile, error) {
+	if name == "" {
+		for _, p := range profiles {
+			if p.IsDefault {
+				return p, nil
+			}
+		}
+		if len(profiles) > 0 {
+			return profiles[0], nil
+		}
+		return Profile{}, fmt.Errorf("no Firefox profiles found")
+	}
+
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("no profile named %q", name)
+}
@@ -0,0 +1,89 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProfilesIni(t *testing.T, dir, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "profiles.ini"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverLegacyDefault(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"abc.default", "xyz.work"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProfilesIni(t, dir, `
+[Profile0]
+Name=default
+IsRelative=1
+Path=abc.default
+Default=1
+
+[Profile1]
+Name=work
+IsRelative=1
+Path=xyz.work
+`)
+
+	t.Setenv("FIREFOX_ROOT", dir)
+	profiles, err := Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := Find(profiles, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != "default" || !def.IsDefault {
+		t.Errorf("Find(\"\") = %+v, want the legacy Default=1 profile", def)
+	}
+}
+
+func TestDiscoverInstallDefault(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"abc.default", "xyz.work"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Firefox 67+ style: no per-profile Default=1 at all, only an
+	// [InstallXXX] section pointing at the default profile's Path.
+	writeProfilesIni(t, dir, `
+[Profile0]
+Name=default
+IsRelative=1
+Path=abc.default
+
+[Profile1]
+Name=work
+IsRelative=1
+Path=xyz.work
+
+[Install8BB2D6336F51B4B2]
+Default=xyz.work
+Locked=1
+`)
+
+	t.Setenv("FIREFOX_ROOT", dir)
+	profiles, err := Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := Find(profiles, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != "work" || !def.IsDefault {
+		t.Errorf("Find(\"\") = %+v, want the [InstallXXX] Default profile", def)
+	}
+}